@@ -0,0 +1,174 @@
+package stormrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/jwt/v2"
+)
+
+// Principal identifies the caller a request was authenticated as.
+type Principal interface {
+	// ID returns a stable identifier for the principal, e.g. a NATS user's
+	// public key or a subject claim from a bearer token.
+	ID() string
+}
+
+// Authenticator authenticates an incoming request, returning the Principal
+// it was made on behalf of. It runs before dispatch in Server's handler; a
+// non-nil error fails the request with an Unauthenticated response instead
+// of invoking the handler.
+type Authenticator func(ctx context.Context, r Request) (Principal, error)
+
+// SubjectPolicy authorizes principal to call subject, once it has already
+// been authenticated by an Authenticator. A non-nil error fails the request
+// with a PermissionDenied response instead of invoking the handler.
+type SubjectPolicy func(principal Principal, subject string) error
+
+type authenticatorOption Authenticator
+
+func (a authenticatorOption) apply(opts *serverOptions) {
+	opts.authenticator = Authenticator(a)
+}
+
+// WithAuthenticator is a ServerOption that runs fn before every request is
+// dispatched, injecting the resulting Principal into the handler's context
+// (see PrincipalFromContext). Requests that fail authentication never reach
+// the registered HandlerFunc.
+func WithAuthenticator(fn Authenticator) ServerOption {
+	return authenticatorOption(fn)
+}
+
+type subjectPolicyOption SubjectPolicy
+
+func (p subjectPolicyOption) apply(opts *serverOptions) {
+	opts.subjectPolicy = SubjectPolicy(p)
+}
+
+// WithSubjectPolicy is a ServerOption that gates access to individual
+// subjects based on the Principal produced by the configured Authenticator.
+// It has no effect unless WithAuthenticator is also set.
+func WithSubjectPolicy(fn SubjectPolicy) ServerOption {
+	return subjectPolicyOption(fn)
+}
+
+type principalCtxKey struct{}
+
+func contextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, p)
+}
+
+// PrincipalFromContext returns the Principal authenticated for the current
+// request, and whether an Authenticator was configured and succeeded.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalCtxKey{}).(Principal)
+	return p, ok
+}
+
+// AuthError is returned by an Authenticator or SubjectPolicy to signal why a
+// request was rejected. Its Error method is what ends up in the response's
+// error header, so messages should be safe to show to a caller.
+type AuthError struct {
+	Code string // "unauthenticated" or "permission_denied"
+	Err  error
+}
+
+func (e *AuthError) Error() string { return fmt.Sprintf("%s: %s", e.Code, e.Err) }
+func (e *AuthError) Unwrap() error { return e.Err }
+
+// ErrUnauthenticated wraps err as an AuthError reporting that the caller
+// could not be authenticated.
+func ErrUnauthenticated(err error) error {
+	return &AuthError{Code: "unauthenticated", Err: err}
+}
+
+// ErrPermissionDenied wraps err as an AuthError reporting that the caller
+// was authenticated but isn't allowed to call the subject.
+func ErrPermissionDenied(err error) error {
+	return &AuthError{Code: "permission_denied", Err: err}
+}
+
+// authenticate runs the server's configured Authenticator and SubjectPolicy,
+// if any, returning the context to dispatch the handler with, or a non-nil
+// error if the request should be rejected without reaching the handler.
+func (s *Server) authenticate(ctx context.Context, r Request, subject string) (context.Context, error) {
+	if s.authenticator == nil {
+		return ctx, nil
+	}
+
+	principal, err := s.authenticator(ctx, r)
+	if err != nil {
+		return ctx, ErrUnauthenticated(err)
+	}
+	ctx = contextWithPrincipal(ctx, principal)
+
+	if s.subjectPolicy != nil {
+		if err := s.subjectPolicy(principal, subject); err != nil {
+			return ctx, ErrPermissionDenied(err)
+		}
+	}
+
+	return ctx, nil
+}
+
+// bearerPrincipal is the Principal produced by BearerTokenAuthenticator.
+type bearerPrincipal string
+
+func (p bearerPrincipal) ID() string { return string(p) }
+
+// authorizationHeader is the NATS header bearer-token authentication reads
+// the "Bearer <token>" credential from.
+const authorizationHeader = "Authorization"
+
+// BearerTokenAuthenticator returns an Authenticator that reads a bearer
+// token from the request's Authorization header and resolves it to a
+// Principal with validate.
+func BearerTokenAuthenticator(validate func(ctx context.Context, token string) (Principal, error)) Authenticator {
+	return func(ctx context.Context, r Request) (Principal, error) {
+		auth := r.Msg.Header.Get(authorizationHeader)
+		token, ok := strings.CutPrefix(auth, "Bearer ")
+		if !ok || token == "" {
+			return nil, errors.New("missing bearer token")
+		}
+
+		return validate(ctx, token)
+	}
+}
+
+// jwtPrincipal is the Principal produced by JWTAuthenticator, wrapping the
+// decoded NATS user claims.
+type jwtPrincipal struct {
+	claims *jwt.UserClaims
+}
+
+func (p jwtPrincipal) ID() string { return p.claims.Subject }
+
+// Claims returns the decoded NATS user claims the principal was created
+// from.
+func (p jwtPrincipal) Claims() *jwt.UserClaims { return p.claims }
+
+// JWTAuthenticator returns an Authenticator that decodes a NATS user JWT
+// from the request's Authorization header and verifies it with verify
+// (typically checking the issuer against a trusted account key).
+func JWTAuthenticator(verify func(claims *jwt.UserClaims) error) Authenticator {
+	return func(ctx context.Context, r Request) (Principal, error) {
+		auth := r.Msg.Header.Get(authorizationHeader)
+		token, ok := strings.CutPrefix(auth, "Bearer ")
+		if !ok || token == "" {
+			return nil, errors.New("missing user JWT")
+		}
+
+		claims, err := jwt.DecodeUserClaims(token)
+		if err != nil {
+			return nil, fmt.Errorf("decode user JWT: %w", err)
+		}
+
+		if err := verify(claims); err != nil {
+			return nil, err
+		}
+
+		return jwtPrincipal{claims: claims}, nil
+	}
+}
@@ -0,0 +1,111 @@
+package stormrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type testPrincipal string
+
+func (p testPrincipal) ID() string { return string(p) }
+
+func TestAuthenticateNoAuthenticatorConfigured(t *testing.T) {
+	s := newTestServer()
+
+	ctx, err := s.authenticate(context.Background(), Request{}, "greet")
+	if err != nil {
+		t.Fatalf("expected no error without an Authenticator, got %v", err)
+	}
+	if _, ok := PrincipalFromContext(ctx); ok {
+		t.Fatal("expected no principal in context without an Authenticator")
+	}
+}
+
+func TestAuthenticateRejectsFailedAuthentication(t *testing.T) {
+	s := newTestServer()
+	s.authenticator = func(ctx context.Context, r Request) (Principal, error) {
+		return nil, errors.New("bad token")
+	}
+
+	_, err := s.authenticate(context.Background(), Request{}, "greet")
+
+	var authErr *AuthError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("expected an *AuthError, got %v", err)
+	}
+	if authErr.Code != "unauthenticated" {
+		t.Fatalf("expected code %q, got %q", "unauthenticated", authErr.Code)
+	}
+}
+
+func TestAuthenticateRejectsDeniedSubjectPolicy(t *testing.T) {
+	s := newTestServer()
+	s.authenticator = func(ctx context.Context, r Request) (Principal, error) {
+		return testPrincipal("alice"), nil
+	}
+	s.subjectPolicy = func(p Principal, subject string) error {
+		return errors.New("not allowed")
+	}
+
+	_, err := s.authenticate(context.Background(), Request{}, "admin.shutdown")
+
+	var authErr *AuthError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("expected an *AuthError, got %v", err)
+	}
+	if authErr.Code != "permission_denied" {
+		t.Fatalf("expected code %q, got %q", "permission_denied", authErr.Code)
+	}
+}
+
+func TestAuthenticateSucceedsAndAttachesPrincipal(t *testing.T) {
+	s := newTestServer()
+	s.authenticator = func(ctx context.Context, r Request) (Principal, error) {
+		return testPrincipal("alice"), nil
+	}
+
+	var gotSubject string
+	s.subjectPolicy = func(p Principal, subject string) error {
+		gotSubject = subject
+		return nil
+	}
+
+	ctx, err := s.authenticate(context.Background(), Request{}, "greet")
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+
+	p, ok := PrincipalFromContext(ctx)
+	if !ok || p.ID() != "alice" {
+		t.Fatalf("expected principal %q in context, got %v (ok=%v)", "alice", p, ok)
+	}
+	if gotSubject != "greet" {
+		t.Fatalf("expected subject policy to see %q, got %q", "greet", gotSubject)
+	}
+}
+
+func TestBearerTokenAuthenticator(t *testing.T) {
+	auth := BearerTokenAuthenticator(func(ctx context.Context, token string) (Principal, error) {
+		if token != "good-token" {
+			return nil, errors.New("invalid token")
+		}
+		return testPrincipal("alice"), nil
+	})
+
+	req := NewRequest("greet", nil)
+	req.Msg.Header.Set(authorizationHeader, "Bearer good-token")
+
+	p, err := auth(context.Background(), req)
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if p.ID() != "alice" {
+		t.Fatalf("expected principal %q, got %q", "alice", p.ID())
+	}
+
+	req = NewRequest("greet", nil)
+	if _, err := auth(context.Background(), req); err == nil {
+		t.Fatal("expected an error for a request with no Authorization header")
+	}
+}
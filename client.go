@@ -0,0 +1,127 @@
+package stormrpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+var defaultClientTimeout = 5 * time.Second
+
+// Client represents a stormRPC client. It contains all functionality for
+// making RPC requests to a stormRPC server.
+type Client struct {
+	nc      *nats.Conn
+	timeout time.Duration
+	codec   Codec
+	mw      []Middleware
+}
+
+// NewClient returns a new instance of a Client.
+func NewClient(natsURL string, opts ...ClientOption) (*Client, error) {
+	options := clientOptions{
+		timeout: defaultClientTimeout,
+		codec:   JSONCodec,
+	}
+
+	for _, o := range opts {
+		o.apply(&options)
+	}
+
+	nc, err := nats.Connect(natsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		nc:      nc,
+		timeout: options.timeout,
+		codec:   options.codec,
+	}, nil
+}
+
+type clientOptions struct {
+	timeout time.Duration
+	codec   Codec
+}
+
+// ClientOption represents functional options for configuring a stormRPC Client.
+type ClientOption interface {
+	apply(*clientOptions)
+}
+
+type clientTimeoutOption time.Duration
+
+func (o clientTimeoutOption) apply(opts *clientOptions) {
+	opts.timeout = time.Duration(o)
+}
+
+// WithClientTimeout is a ClientOption that sets the default deadline applied
+// to requests that don't already carry one via context.
+func WithClientTimeout(d time.Duration) ClientOption {
+	return clientTimeoutOption(d)
+}
+
+type clientCodecOption struct{ codec Codec }
+
+func (o clientCodecOption) apply(opts *clientOptions) {
+	opts.codec = o.codec
+}
+
+// WithClientCodec is a ClientOption that sets the Codec used to encode
+// outgoing requests; the negotiated Content-Type is sent along on every
+// request so the server can pick a matching decoder.
+func WithClientCodec(c Codec) ClientOption {
+	return clientCodecOption{codec: c}
+}
+
+// Use sets the middleware applied to every call made through Do, composed
+// in the order given with mw[0] outermost. Use must be called before the Do
+// calls that should pick it up.
+func (c *Client) Use(mw ...Middleware) {
+	c.mw = mw
+}
+
+// Do issues req as a unary RPC call and waits for the response, running it
+// through any middleware installed via Use. If ctx carries a deadline, it's
+// sent along on the request so the server can bound its own context to
+// whatever time the caller actually has left.
+func (c *Client) Do(ctx context.Context, req Request) Response {
+	fn := c.call
+	for i := len(c.mw) - 1; i >= 0; i-- {
+		fn = c.mw[i](fn)
+	}
+
+	return fn(ctx, req)
+}
+
+func (c *Client) call(ctx context.Context, req Request) Response {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	if req.Msg.Header == nil {
+		req.Msg.Header = nats.Header{}
+	}
+	req.Msg.Header.Set(contentTypeHeader, c.codec.ContentType())
+	if dl, ok := ctx.Deadline(); ok {
+		setDeadlineHeader(req.Msg.Header, dl)
+	}
+
+	msg, err := c.nc.RequestMsgWithContext(ctx, req.Msg)
+	if err != nil {
+		return NewErrorResponse(req, err)
+	}
+
+	if msg.Header != nil {
+		if errMsg := msg.Header.Get(errorHeader); errMsg != "" {
+			return Response{Msg: msg, Err: errors.New(errMsg)}
+		}
+	}
+
+	return Response{Msg: msg}
+}
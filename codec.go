@@ -0,0 +1,105 @@
+package stormrpc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// contentTypeHeader carries the negotiated Codec's content type on both the
+// request and the response, so a handler and its caller can agree on wire
+// format without a side channel.
+const contentTypeHeader = "Content-Type"
+
+// Codec marshals and unmarshals the bodies of stormRPC requests and
+// responses. Built-in implementations are provided for JSON, Protobuf and
+// msgpack; custom codecs can be registered for negotiation with
+// RegisterCodec, and a server's default codec is set with WithCodec.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+	ContentType() string
+}
+
+// jsonCodec is the default Codec used when no Content-Type header is present.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) ContentType() string                { return "application/json" }
+
+// JSONCodec is a Codec that marshals bodies as JSON.
+var JSONCodec Codec = jsonCodec{}
+
+// protoCodec marshals bodies as Protobuf. Marshal and Unmarshal return an
+// error if v does not implement proto.Message.
+type protoCodec struct{}
+
+func (protoCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("stormrpc: %T does not implement proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protoCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("stormrpc: %T does not implement proto.Message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+func (protoCodec) ContentType() string { return "application/protobuf" }
+
+// ProtoCodec is a Codec that marshals bodies as Protobuf.
+var ProtoCodec Codec = protoCodec{}
+
+// msgpackCodec marshals bodies as msgpack.
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error)      { return msgpack.Marshal(v) }
+func (msgpackCodec) Unmarshal(data []byte, v any) error { return msgpack.Unmarshal(data, v) }
+func (msgpackCodec) ContentType() string                { return "application/msgpack" }
+
+// MsgpackCodec is a Codec that marshals bodies as msgpack.
+var MsgpackCodec Codec = msgpackCodec{}
+
+var codecsByContentType = map[string]Codec{
+	JSONCodec.ContentType():    JSONCodec,
+	ProtoCodec.ContentType():   ProtoCodec,
+	MsgpackCodec.ContentType(): MsgpackCodec,
+}
+
+// RegisterCodec makes a Codec available for negotiation by Content-Type.
+// Built-in codecs are already registered; call this to add custom ones.
+func RegisterCodec(c Codec) {
+	codecsByContentType[c.ContentType()] = c
+}
+
+// codecFor resolves the Codec for an incoming Content-Type header, falling
+// back to def when the header is absent or unrecognized.
+func codecFor(contentType string, def Codec) Codec {
+	if contentType == "" {
+		return def
+	}
+	if c, ok := codecsByContentType[contentType]; ok {
+		return c
+	}
+	return def
+}
+
+type codecOption struct{ codec Codec }
+
+func (o codecOption) apply(opts *serverOptions) {
+	opts.codec = o.codec
+}
+
+// WithCodec is a ServerOption that sets the default Codec used to decode
+// requests and encode responses when no Content-Type header is present.
+func WithCodec(c Codec) ServerOption {
+	return codecOption{codec: c}
+}
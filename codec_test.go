@@ -0,0 +1,144 @@
+package stormrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type codecTestPayload struct {
+	Name string `json:"name" msgpack:"name"`
+	Age  int    `json:"age" msgpack:"age"`
+}
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	testCodecRoundTrip(t, JSONCodec, "application/json")
+}
+
+func TestMsgpackCodecRoundTrip(t *testing.T) {
+	testCodecRoundTrip(t, MsgpackCodec, "application/msgpack")
+}
+
+func TestProtoCodecRoundTrip(t *testing.T) {
+	if ProtoCodec.ContentType() != "application/protobuf" {
+		t.Fatalf("expected content type %q, got %q", "application/protobuf", ProtoCodec.ContentType())
+	}
+
+	in := wrapperspb.String("ada")
+
+	data, err := ProtoCodec.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	out := &wrapperspb.StringValue{}
+	if err := ProtoCodec.Unmarshal(data, out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if out.GetValue() != in.GetValue() {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", out, in)
+	}
+}
+
+func testCodecRoundTrip(t *testing.T, c Codec, wantContentType string) {
+	t.Helper()
+
+	if c.ContentType() != wantContentType {
+		t.Fatalf("expected content type %q, got %q", wantContentType, c.ContentType())
+	}
+
+	in := codecTestPayload{Name: "ada", Age: 30}
+
+	data, err := c.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var out codecTestPayload
+	if err := c.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if out != in {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", out, in)
+	}
+}
+
+// TestHandleTypedNegotiatesContentType drives an actual request through a
+// Server/Client pair to prove the Content-Type header is read off the
+// incoming NATS message and echoed on the response, and that HandleTyped
+// decodes/encodes using the negotiated Codec rather than the server's
+// default.
+func TestHandleTypedNegotiatesContentType(t *testing.T) {
+	url := startTestServer(t)
+
+	s, err := NewServer("svc", url)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	HandleTyped(s, "greet", func(ctx context.Context, req *codecTestPayload) (*codecTestPayload, error) {
+		return &codecTestPayload{Name: req.Name, Age: req.Age + 1}, nil
+	})
+
+	go func() {
+		if err := s.Run(); err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+	})
+
+	c, err := NewClient(url, WithClientCodec(MsgpackCodec))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	in := codecTestPayload{Name: "ada", Age: 30}
+	data, err := MsgpackCodec.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp := c.Do(ctx, NewRequest("greet", data))
+	if resp.Err != nil {
+		t.Fatalf("Do: %v", resp.Err)
+	}
+
+	if gotContentType := resp.Header.Get(contentTypeHeader); gotContentType != MsgpackCodec.ContentType() {
+		t.Fatalf("expected response Content-Type %q, got %q", MsgpackCodec.ContentType(), gotContentType)
+	}
+
+	var out codecTestPayload
+	if err := MsgpackCodec.Unmarshal(resp.Data, &out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	want := codecTestPayload{Name: "ada", Age: 31}
+	if out != want {
+		t.Fatalf("expected %+v, got %+v", want, out)
+	}
+}
+
+func TestCodecFor(t *testing.T) {
+	if got := codecFor("", ProtoCodec); got != ProtoCodec {
+		t.Fatalf("expected empty content type to fall back to def")
+	}
+	if got := codecFor("application/bogus", ProtoCodec); got != ProtoCodec {
+		t.Fatalf("expected unknown content type to fall back to def")
+	}
+	if got := codecFor(MsgpackCodec.ContentType(), ProtoCodec); got != MsgpackCodec {
+		t.Fatalf("expected known content type to resolve to its codec")
+	}
+}
@@ -0,0 +1,20 @@
+package stormrpc
+
+import "context"
+
+type queueGroupCtxKey struct{}
+
+// contextWithQueueGroup attaches the resolved NATS queue-group name (the
+// server's name) that a request was dispatched under.
+func contextWithQueueGroup(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, queueGroupCtxKey{}, name)
+}
+
+// QueueGroupFromContext returns the NATS queue-group name a request was
+// dispatched under, and whether one was present. Middleware such as
+// otelstorm's uses this to label spans and metrics with the serving group,
+// since it isn't otherwise reachable from inside a HandlerFunc.
+func QueueGroupFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(queueGroupCtxKey{}).(string)
+	return name, ok
+}
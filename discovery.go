@@ -0,0 +1,202 @@
+package stormrpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// These mirror the subject layout used by the NATS "services" control-plane
+// pattern: a bare verb for bus-wide discovery, a verb scoped to the service
+// name, and a verb scoped to the specific server instance.
+const (
+	srvSubjectPing  = "$SRV.PING"
+	srvSubjectInfo  = "$SRV.INFO"
+	srvSubjectStats = "$SRV.STATS"
+)
+
+// ServerPing is the payload returned for $SRV.PING discovery requests.
+type ServerPing struct {
+	Name    string `json:"name"`
+	ID      string `json:"id"`
+	Version string `json:"version"`
+}
+
+// EndpointInfo describes a single registered endpoint for $SRV.INFO discovery.
+type EndpointInfo struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+}
+
+// ServerInfo is the payload returned for $SRV.INFO discovery requests.
+type ServerInfo struct {
+	Name      string            `json:"name"`
+	ID        string            `json:"id"`
+	Version   string            `json:"version"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Endpoints []EndpointInfo    `json:"endpoints"`
+}
+
+// EndpointStats holds the request/error/latency counters for a single registered endpoint.
+type EndpointStats struct {
+	Name                  string        `json:"name"`
+	Subject               string        `json:"subject"`
+	NumRequests           int64         `json:"num_requests"`
+	NumErrors             int64         `json:"num_errors"`
+	ProcessingTime        time.Duration `json:"processing_time"`
+	AverageProcessingTime time.Duration `json:"average_processing_time"`
+	LastError             string        `json:"last_error,omitempty"`
+}
+
+// ServerStats is the payload returned for $SRV.STATS discovery requests, and by Server.Stats.
+type ServerStats struct {
+	Name      string          `json:"name"`
+	ID        string          `json:"id"`
+	Version   string          `json:"version"`
+	Started   time.Time       `json:"started"`
+	Endpoints []EndpointStats `json:"endpoints"`
+}
+
+// endpointStats accumulates the monitoring counters for one registered handler.
+type endpointStats struct {
+	mu             sync.Mutex
+	numRequests    int64
+	numErrors      int64
+	processingTime time.Duration
+	lastError      string
+}
+
+func (e *endpointStats) record(d time.Duration, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.numRequests++
+	e.processingTime += d
+	if err != nil {
+		e.numErrors++
+		e.lastError = err.Error()
+	}
+}
+
+func (e *endpointStats) snapshot(subject string) EndpointStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var avg time.Duration
+	if e.numRequests > 0 {
+		avg = e.processingTime / time.Duration(e.numRequests)
+	}
+
+	return EndpointStats{
+		Name:                  subject,
+		Subject:               subject,
+		NumRequests:           e.numRequests,
+		NumErrors:             e.numErrors,
+		ProcessingTime:        e.processingTime,
+		AverageProcessingTime: avg,
+		LastError:             e.lastError,
+	}
+}
+
+// Stats returns a snapshot of the request, error and latency counters for
+// every registered endpoint. It reports the same information published on
+// the $SRV.STATS discovery subject, so in-process callers don't need to
+// round-trip through NATS to scrape their own metrics.
+func (s *Server) Stats() ServerStats {
+	s.mu.Lock()
+	handlers := make([]*handler, 0, len(s.handlers))
+	for _, h := range s.handlers {
+		handlers = append(handlers, h)
+	}
+	s.mu.Unlock()
+
+	stats := ServerStats{
+		Name:      s.name,
+		ID:        s.id,
+		Version:   s.version,
+		Started:   s.started,
+		Endpoints: make([]EndpointStats, 0, len(handlers)),
+	}
+
+	for _, h := range handlers {
+		stats.Endpoints = append(stats.Endpoints, h.stats.snapshot(h.subject))
+	}
+
+	return stats
+}
+
+func (s *Server) info() ServerInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info := ServerInfo{
+		Name:      s.name,
+		ID:        s.id,
+		Version:   s.version,
+		Metadata:  s.metadata,
+		Endpoints: make([]EndpointInfo, 0, len(s.handlers)),
+	}
+
+	for subject := range s.handlers {
+		info.Endpoints = append(info.Endpoints, EndpointInfo{Name: subject, Subject: subject})
+	}
+
+	return info
+}
+
+// startDiscovery subscribes the server to the NATS micro-service discovery
+// subjects so that a stormRPC server is auto-discoverable by any NATS
+// monitoring tool with zero extra configuration: $SRV.PING, $SRV.INFO and
+// $SRV.STATS, each also scoped to this server's name and instance ID.
+func (s *Server) startDiscovery() error {
+	verbs := []struct {
+		subject string
+		handle  nats.MsgHandler
+	}{
+		{srvSubjectPing, s.handlePing},
+		{srvSubjectInfo, s.handleInfo},
+		{srvSubjectStats, s.handleStats},
+	}
+
+	for _, v := range verbs {
+		for _, subject := range []string{v.subject, v.subject + "." + s.name, v.subject + "." + s.name + "." + s.id} {
+			sub, err := s.nc.Subscribe(subject, v.handle)
+			if err != nil {
+				return err
+			}
+
+			s.mu.Lock()
+			s.discoverySubs = append(s.discoverySubs, sub)
+			s.mu.Unlock()
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) handlePing(msg *nats.Msg) {
+	s.respondJSON(msg, ServerPing{Name: s.name, ID: s.id, Version: s.version})
+}
+
+func (s *Server) handleInfo(msg *nats.Msg) {
+	s.respondJSON(msg, s.info())
+}
+
+func (s *Server) handleStats(msg *nats.Msg) {
+	s.respondJSON(msg, s.Stats())
+}
+
+func (s *Server) respondJSON(msg *nats.Msg, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.errorHandler(context.Background(), err)
+		return
+	}
+
+	if err := msg.Respond(data); err != nil {
+		s.errorHandler(context.Background(), err)
+	}
+}
@@ -0,0 +1,67 @@
+package stormrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestServer() *Server {
+	return &Server{
+		id:           "test-id",
+		name:         "test-server",
+		version:      "v1.2.3",
+		metadata:     map[string]string{"region": "us-east"},
+		handlers:     make(map[string]*handler),
+		errorHandler: func(context.Context, error) {},
+	}
+}
+
+func TestServerInfo(t *testing.T) {
+	s := newTestServer()
+	s.handlers["greet"] = &handler{subject: "greet"}
+
+	info := s.info()
+	if info.Name != s.name || info.ID != s.id || info.Version != s.version {
+		t.Fatalf("info fields don't match server: %+v", info)
+	}
+	if info.Metadata["region"] != "us-east" {
+		t.Fatalf("expected metadata to be carried through, got %+v", info.Metadata)
+	}
+	if len(info.Endpoints) != 1 || info.Endpoints[0].Subject != "greet" {
+		t.Fatalf("expected one endpoint for %q, got %+v", "greet", info.Endpoints)
+	}
+}
+
+func TestServerStats(t *testing.T) {
+	s := newTestServer()
+	s.started = time.Now()
+	h := &handler{subject: "greet"}
+	s.handlers["greet"] = h
+
+	h.stats.record(10*time.Millisecond, nil)
+	h.stats.record(20*time.Millisecond, errors.New("boom"))
+
+	stats := s.Stats()
+	if stats.Name != s.name || stats.ID != s.id {
+		t.Fatalf("stats fields don't match server: %+v", stats)
+	}
+	if len(stats.Endpoints) != 1 {
+		t.Fatalf("expected one endpoint, got %d", len(stats.Endpoints))
+	}
+
+	ep := stats.Endpoints[0]
+	if ep.NumRequests != 2 {
+		t.Fatalf("expected 2 requests recorded, got %d", ep.NumRequests)
+	}
+	if ep.NumErrors != 1 {
+		t.Fatalf("expected 1 error recorded, got %d", ep.NumErrors)
+	}
+	if ep.LastError != "boom" {
+		t.Fatalf("expected last error %q, got %q", "boom", ep.LastError)
+	}
+	if ep.AverageProcessingTime != 15*time.Millisecond {
+		t.Fatalf("expected average processing time of 15ms, got %s", ep.AverageProcessingTime)
+	}
+}
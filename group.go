@@ -0,0 +1,28 @@
+package stormrpc
+
+// Group scopes a subject prefix and a chain of middleware across a set of
+// handlers, similar to the route groups found in popular HTTP routers.
+// Create one with Server.Group, and nest further groups with Group.Group.
+type Group struct {
+	server *Server
+	prefix string
+	mw     []Middleware
+}
+
+// Group returns a nested Group whose prefix is appended to this group's
+// prefix, and whose middleware is composed after this group's own
+// middleware.
+func (g *Group) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{
+		server: g.server,
+		prefix: g.prefix + prefix,
+		mw:     append(composeMiddleware(g.mw), mw...),
+	}
+}
+
+// Handle registers fn on g.prefix+subject. The handler is wrapped with the
+// server's global middleware, then this group's middleware (and that of any
+// parent groups it was nested from), then mw, in that order.
+func (g *Group) Handle(subject string, fn HandlerFunc, mw ...Middleware) {
+	g.server.register(g.prefix+subject, fn, composeMiddleware(g.server.mw, g.mw, mw))
+}
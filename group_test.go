@@ -0,0 +1,116 @@
+package stormrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func mwAppend(tag string, log *[]string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, r Request) Response {
+			*log = append(*log, tag)
+			return next(ctx, r)
+		}
+	}
+}
+
+func TestGroupMiddlewareComposition(t *testing.T) {
+	url := startTestServer(t)
+
+	s, err := NewServer("svc", url)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	var log []string
+	s.Use(mwAppend("global", &log))
+
+	g := s.Group("/v1", mwAppend("group", &log))
+	g.Handle("/greet", func(ctx context.Context, r Request) Response {
+		log = append(log, "handler")
+		return NewResponse(r, nil)
+	}, mwAppend("handler-local", &log))
+
+	go func() {
+		if err := s.Run(); err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+	})
+
+	c, err := NewClient(url)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if resp := c.Do(ctx, NewRequest("/v1/greet", nil)); resp.Err != nil {
+		t.Fatalf("Do: %v", resp.Err)
+	}
+
+	want := []string{"global", "group", "handler-local", "handler"}
+	if len(log) != len(want) {
+		t.Fatalf("expected call order %v, got %v", want, log)
+	}
+	for i := range want {
+		if log[i] != want[i] {
+			t.Fatalf("expected call order %v, got %v", want, log)
+		}
+	}
+}
+
+func TestHandleAfterRunSubscribesImmediately(t *testing.T) {
+	url := startTestServer(t)
+
+	s, err := NewServer("svc", url)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	go func() {
+		if err := s.Run(); err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+	})
+
+	// Give Run a moment to finish its initial subscribe pass before we
+	// register a late handler, so this genuinely exercises the
+	// after-Run path rather than racing Run's own setup.
+	time.Sleep(50 * time.Millisecond)
+
+	s.Handle("late", func(ctx context.Context, r Request) Response {
+		return NewResponse(r, []byte("pong"))
+	})
+
+	c, err := NewClient(url)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp := c.Do(ctx, NewRequest("late", nil))
+	if resp.Err != nil {
+		t.Fatalf("Do: %v", resp.Err)
+	}
+	if string(resp.Data) != "pong" {
+		t.Fatalf("expected %q, got %q", "pong", resp.Data)
+	}
+}
@@ -0,0 +1,59 @@
+package stormrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// errorHeader carries a failed handler's error message on the reply
+// message's NATS header.
+const errorHeader = "Stormrpc-Error"
+
+// deadlineHeader carries the caller's deadline, RFC3339Nano encoded, so the
+// server can bound ctx to whatever time the caller actually has left
+// instead of always using its own default timeout.
+const deadlineHeader = "Stormrpc-Deadline"
+
+// parseDeadlineHeader returns the deadline carried on h, or the zero Time
+// if h has none or it can't be parsed.
+func parseDeadlineHeader(h nats.Header) time.Time {
+	if h == nil {
+		return time.Time{}
+	}
+
+	v := h.Get(deadlineHeader)
+	if v == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
+
+// setDeadlineHeader stamps h with deadline so the receiving server can
+// bound its context to it.
+func setDeadlineHeader(h nats.Header, deadline time.Time) {
+	h.Set(deadlineHeader, deadline.Format(time.RFC3339Nano))
+}
+
+type headerCtxKey struct{}
+
+// newContextWithHeaders attaches the request's incoming NATS header to ctx
+// so handlers (and middleware) can read caller-supplied headers without
+// threading the Request through every call.
+func newContextWithHeaders(ctx context.Context, h nats.Header) context.Context {
+	return context.WithValue(ctx, headerCtxKey{}, h)
+}
+
+// HeaderFromContext returns the NATS header of the request being handled in
+// ctx, or nil if ctx wasn't derived from a stormRPC handler invocation.
+func HeaderFromContext(ctx context.Context) nats.Header {
+	h, _ := ctx.Value(headerCtxKey{}).(nats.Header)
+	return h
+}
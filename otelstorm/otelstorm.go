@@ -0,0 +1,218 @@
+// Package otelstorm provides OpenTelemetry tracing and metrics middleware
+// for stormRPC servers and clients: spans named after the subject, W3C
+// trace context propagated through NATS headers, and RED (rate, errors,
+// duration) metrics plus request/response payload size histograms,
+// recorded via an otel.Meter.
+package otelstorm
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/zzycn003/stormrpc"
+)
+
+const instrumentationName = "github.com/zzycn003/stormrpc/otelstorm"
+
+// Option configures the server and client Middleware built by
+// ServerMiddleware and ClientMiddleware.
+type Option func(*config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagator     propagation.TextMapPropagator
+}
+
+// WithTracerProvider sets the TracerProvider used to create spans. The
+// global provider is used if this option is omitted.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) { c.tracerProvider = tp }
+}
+
+// WithMeterProvider sets the MeterProvider used to record RED metrics. The
+// global provider is used if this option is omitted.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) { c.meterProvider = mp }
+}
+
+// WithPropagator sets the propagator used to inject/extract trace context
+// through NATS headers. The global propagator is used if this option is
+// omitted.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(c *config) { c.propagator = p }
+}
+
+type instrumentation struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	requests   metric.Int64Counter
+	errors     metric.Int64Counter
+	duration   metric.Float64Histogram
+	reqSize    metric.Int64Histogram
+	respSize   metric.Int64Histogram
+}
+
+func newInstrumentation(opts ...Option) (*instrumentation, error) {
+	c := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+		propagator:     otel.GetTextMapPropagator(),
+	}
+	for _, o := range opts {
+		o(&c)
+	}
+
+	meter := c.meterProvider.Meter(instrumentationName)
+
+	requests, err := meter.Int64Counter("stormrpc.server.requests", metric.WithDescription("Number of RPC requests handled, by subject"))
+	if err != nil {
+		return nil, err
+	}
+
+	errs, err := meter.Int64Counter("stormrpc.server.errors", metric.WithDescription("Number of RPC requests that returned an error, by subject"))
+	if err != nil {
+		return nil, err
+	}
+
+	duration, err := meter.Float64Histogram("stormrpc.server.duration", metric.WithDescription("RPC request duration in milliseconds, by subject"), metric.WithUnit("ms"))
+	if err != nil {
+		return nil, err
+	}
+
+	reqSize, err := meter.Int64Histogram("stormrpc.request_size", metric.WithDescription("RPC request body size in bytes, by subject"), metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+
+	respSize, err := meter.Int64Histogram("stormrpc.response_size", metric.WithDescription("RPC response body size in bytes, by subject"), metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &instrumentation{
+		tracer:     c.tracerProvider.Tracer(instrumentationName),
+		propagator: c.propagator,
+		requests:   requests,
+		errors:     errs,
+		duration:   duration,
+		reqSize:    reqSize,
+		respSize:   respSize,
+	}, nil
+}
+
+// headerCarrier adapts a nats.Header to otel's propagation.TextMapCarrier.
+type headerCarrier nats.Header
+
+func (c headerCarrier) Get(key string) string { return nats.Header(c).Get(key) }
+func (c headerCarrier) Set(key, value string) { nats.Header(c).Set(key, value) }
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// ServerMiddleware returns a stormrpc.Middleware that starts a span named
+// after the request subject, extracts any incoming W3C trace context from
+// the request's NATS headers, and records RED metrics for every request.
+func ServerMiddleware(opts ...Option) (stormrpc.Middleware, error) {
+	inst, err := newInstrumentation(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next stormrpc.HandlerFunc) stormrpc.HandlerFunc {
+		return func(ctx context.Context, r stormrpc.Request) stormrpc.Response {
+			if r.Msg.Header != nil {
+				ctx = inst.propagator.Extract(ctx, headerCarrier(r.Msg.Header))
+			}
+
+			subject := r.Msg.Subject
+
+			attrs := []attribute.KeyValue{attribute.String("stormrpc.subject", subject)}
+			if dl, ok := ctx.Deadline(); ok {
+				attrs = append(attrs, attribute.String("stormrpc.deadline", dl.Format(time.RFC3339Nano)))
+			}
+			if group, ok := stormrpc.QueueGroupFromContext(ctx); ok {
+				attrs = append(attrs, attribute.String("stormrpc.queue_group", group))
+			}
+
+			ctx, span := inst.tracer.Start(ctx, subject, trace.WithAttributes(attrs...), trace.WithSpanKind(trace.SpanKindServer))
+			defer span.End()
+
+			start := time.Now()
+			resp := next(ctx, r)
+			elapsed := time.Since(start)
+
+			metricAttrs := metric.WithAttributeSet(attribute.NewSet(attrs[0]))
+			inst.requests.Add(ctx, 1, metricAttrs)
+			inst.duration.Record(ctx, float64(elapsed.Milliseconds()), metricAttrs)
+			inst.reqSize.Record(ctx, int64(len(r.Msg.Data)), metricAttrs)
+			if resp.Msg != nil {
+				inst.respSize.Record(ctx, int64(len(resp.Msg.Data)), metricAttrs)
+			}
+
+			if resp.Err != nil {
+				inst.errors.Add(ctx, 1, metricAttrs)
+				span.RecordError(resp.Err)
+				span.SetStatus(codes.Error, resp.Err.Error())
+			} else {
+				span.SetStatus(codes.Ok, "")
+			}
+
+			return resp
+		}
+	}, nil
+}
+
+// ClientMiddleware returns a stormrpc.Middleware for instrumenting outgoing
+// calls made through Client.Do (install it with Client.Use): it starts a
+// client span named after the subject and injects the current W3C trace
+// context into the outgoing request's NATS headers so the server-side span
+// can be linked as a child.
+func ClientMiddleware(opts ...Option) (stormrpc.Middleware, error) {
+	inst, err := newInstrumentation(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next stormrpc.HandlerFunc) stormrpc.HandlerFunc {
+		return func(ctx context.Context, r stormrpc.Request) stormrpc.Response {
+			subject := r.Msg.Subject
+
+			ctx, span := inst.tracer.Start(ctx, subject, trace.WithSpanKind(trace.SpanKindClient))
+			defer span.End()
+
+			if r.Msg.Header == nil {
+				r.Msg.Header = nats.Header{}
+			}
+			inst.propagator.Inject(ctx, headerCarrier(r.Msg.Header))
+
+			metricAttrs := metric.WithAttributeSet(attribute.NewSet(attribute.String("stormrpc.subject", subject)))
+			inst.reqSize.Record(ctx, int64(len(r.Msg.Data)), metricAttrs)
+
+			resp := next(ctx, r)
+			if resp.Msg != nil {
+				inst.respSize.Record(ctx, int64(len(resp.Msg.Data)), metricAttrs)
+			}
+			if resp.Err != nil {
+				span.RecordError(resp.Err)
+				span.SetStatus(codes.Error, resp.Err.Error())
+			} else {
+				span.SetStatus(codes.Ok, "")
+			}
+
+			return resp
+		}
+	}, nil
+}
@@ -0,0 +1,154 @@
+package otelstorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/zzycn003/stormrpc"
+)
+
+func TestServerMiddlewareCallsThroughAndPreservesError(t *testing.T) {
+	mw, err := ServerMiddleware()
+	if err != nil {
+		t.Fatalf("ServerMiddleware: %v", err)
+	}
+
+	wantErr := errors.New("boom")
+	var called bool
+	handler := mw(func(ctx context.Context, r stormrpc.Request) stormrpc.Response {
+		called = true
+		return stormrpc.NewErrorResponse(r, wantErr)
+	})
+
+	req := stormrpc.NewRequest("greet", nil)
+	resp := handler(context.Background(), req)
+
+	if !called {
+		t.Fatal("expected the wrapped handler to be called")
+	}
+	if resp.Err != wantErr {
+		t.Fatalf("expected handler's error to pass through, got %v", resp.Err)
+	}
+}
+
+func TestClientMiddlewareInjectsTraceContextHeader(t *testing.T) {
+	mw, err := ClientMiddleware(WithPropagator(propagation.TraceContext{}))
+	if err != nil {
+		t.Fatalf("ClientMiddleware: %v", err)
+	}
+
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01},
+		SpanID:     trace.SpanID{0x01},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), parent)
+
+	var gotHeader nats.Header
+	next := mw(func(ctx context.Context, r stormrpc.Request) stormrpc.Response {
+		gotHeader = r.Msg.Header
+		return stormrpc.NewResponse(r, nil)
+	})
+
+	req := stormrpc.NewRequest("greet", nil)
+	if resp := next(ctx, req); resp.Err != nil {
+		t.Fatalf("unexpected error: %v", resp.Err)
+	}
+
+	if gotHeader == nil {
+		t.Fatal("expected the outgoing request to carry a header")
+	}
+	if gotHeader.Get("traceparent") == "" {
+		t.Fatalf("expected a traceparent header to be injected, got %v", gotHeader)
+	}
+}
+
+func TestServerMiddlewareRecordsRequestAndResponseSize(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	mw, err := ServerMiddleware(WithMeterProvider(mp))
+	if err != nil {
+		t.Fatalf("ServerMiddleware: %v", err)
+	}
+
+	handler := mw(func(ctx context.Context, r stormrpc.Request) stormrpc.Response {
+		return stormrpc.NewResponse(r, []byte("0123456789"))
+	})
+
+	req := stormrpc.NewRequest("greet", []byte("12345"))
+	if resp := handler(context.Background(), req); resp.Err != nil {
+		t.Fatalf("unexpected error: %v", resp.Err)
+	}
+
+	if got := histogramSum(t, reader, "stormrpc.request_size"); got != 5 {
+		t.Fatalf("expected request size 5, got %v", got)
+	}
+	if got := histogramSum(t, reader, "stormrpc.response_size"); got != 10 {
+		t.Fatalf("expected response size 10, got %v", got)
+	}
+}
+
+func TestClientMiddlewareRecordsRequestAndResponseSize(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	mw, err := ClientMiddleware(WithMeterProvider(mp))
+	if err != nil {
+		t.Fatalf("ClientMiddleware: %v", err)
+	}
+
+	next := mw(func(ctx context.Context, r stormrpc.Request) stormrpc.Response {
+		return stormrpc.NewResponse(r, []byte("abc"))
+	})
+
+	req := stormrpc.NewRequest("greet", []byte("ab"))
+	if resp := next(context.Background(), req); resp.Err != nil {
+		t.Fatalf("unexpected error: %v", resp.Err)
+	}
+
+	if got := histogramSum(t, reader, "stormrpc.request_size"); got != 2 {
+		t.Fatalf("expected request size 2, got %v", got)
+	}
+	if got := histogramSum(t, reader, "stormrpc.response_size"); got != 3 {
+		t.Fatalf("expected response size 3, got %v", got)
+	}
+}
+
+// histogramSum collects the current metrics from reader and returns the sum
+// of all data points recorded for the int64 histogram named name.
+func histogramSum(t *testing.T, reader sdkmetric.Reader, name string) int64 {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			hist, ok := m.Data.(metricdata.Histogram[int64])
+			if !ok {
+				t.Fatalf("metric %s is not an int64 histogram: %T", name, m.Data)
+			}
+			var sum int64
+			for _, dp := range hist.DataPoints {
+				sum += dp.Sum
+			}
+			return sum
+		}
+	}
+
+	t.Fatalf("metric %s not found", name)
+	return 0
+}
@@ -0,0 +1,16 @@
+package stormrpc
+
+import "github.com/nats-io/nats.go"
+
+// Request is a single incoming stormRPC request. It embeds the underlying
+// NATS message, so r.Subject, r.Data and r.Header all refer to the message
+// as received.
+type Request struct {
+	*nats.Msg
+}
+
+// NewRequest builds a Request for subject carrying data as its body, ready
+// to be used as the outgoing message of a Client call.
+func NewRequest(subject string, data []byte) Request {
+	return Request{Msg: &nats.Msg{Subject: subject, Data: data, Header: nats.Header{}}}
+}
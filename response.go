@@ -0,0 +1,24 @@
+package stormrpc
+
+import "github.com/nats-io/nats.go"
+
+// Response is a single stormRPC response. It embeds the underlying NATS
+// message, so r.Header mutates the same headers that get sent back to the
+// caller via nats.Msg.RespondMsg. Err is non-nil when the handler that
+// produced this Response failed; the handler dispatch loop is responsible
+// for surfacing it on the wire via errorHeader.
+type Response struct {
+	*nats.Msg
+	Err error
+}
+
+// NewResponse returns a successful Response carrying data as its body.
+func NewResponse(r Request, data []byte) Response {
+	return Response{Msg: &nats.Msg{Data: data, Header: nats.Header{}}}
+}
+
+// NewErrorResponse returns a Response reporting that handling r failed
+// with err.
+func NewErrorResponse(r Request, err error) Response {
+	return Response{Msg: &nats.Msg{Header: nats.Header{}}, Err: err}
+}
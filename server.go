@@ -2,9 +2,13 @@ package stormrpc
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nuid"
 )
 
 var defaultServerTimeout = 5 * time.Second
@@ -12,18 +16,34 @@ var defaultServerTimeout = 5 * time.Second
 // Server represents a stormRPC server. It contains all functionality for handling RPC requests.
 type Server struct {
 	nc             *nats.Conn
+	id             string
 	name           string
+	version        string
+	metadata       map[string]string
+	started        time.Time
 	shutdownSignal chan struct{}
-	handlerFuncs   map[string]HandlerFunc
 	errorHandler   ErrorHandler
 	timeout        time.Duration
 	mw             []Middleware
+
+	codec         Codec
+	authenticator Authenticator
+	subjectPolicy SubjectPolicy
+
+	mu            sync.Mutex
+	running       bool
+	handlers      map[string]*handler
+	discoverySubs []*nats.Subscription
+	subs          []*nats.Subscription
+	inFlight      sync.WaitGroup
+	inFlightCount atomic.Int64
 }
 
 // NewServer returns a new instance of a Server.
 func NewServer(name, natsURL string, opts ...ServerOption) (*Server, error) {
 	options := serverOptions{
 		errorHandler: func(ctx context.Context, err error) {},
+		codec:        JSONCodec,
 	}
 
 	for _, o := range opts {
@@ -37,16 +57,27 @@ func NewServer(name, natsURL string, opts ...ServerOption) (*Server, error) {
 
 	return &Server{
 		nc:             nc,
+		id:             nuid.Next(),
 		name:           name,
+		version:        options.version,
+		metadata:       options.metadata,
+		codec:          options.codec,
+		authenticator:  options.authenticator,
+		subjectPolicy:  options.subjectPolicy,
 		shutdownSignal: make(chan struct{}),
-		handlerFuncs:   make(map[string]HandlerFunc),
+		handlers:       make(map[string]*handler),
 		timeout:        defaultServerTimeout,
 		errorHandler:   options.errorHandler,
 	}, nil
 }
 
 type serverOptions struct {
-	errorHandler ErrorHandler
+	errorHandler  ErrorHandler
+	version       string
+	metadata      map[string]string
+	codec         Codec
+	authenticator Authenticator
+	subjectPolicy SubjectPolicy
 }
 
 // ServerOption represents functional options for configuring a stormRPC Server.
@@ -65,6 +96,30 @@ func WithErrorHandler(fn ErrorHandler) ServerOption {
 	return errorHandlerOption(fn)
 }
 
+type versionOption string
+
+func (v versionOption) apply(opts *serverOptions) {
+	opts.version = string(v)
+}
+
+// WithVersion is a ServerOption that sets the version reported by the server's
+// $SRV.PING, $SRV.INFO and $SRV.STATS discovery responses, and by Server.Stats.
+func WithVersion(version string) ServerOption {
+	return versionOption(version)
+}
+
+type metadataOption map[string]string
+
+func (m metadataOption) apply(opts *serverOptions) {
+	opts.metadata = map[string]string(m)
+}
+
+// WithMetadata is a ServerOption that attaches arbitrary metadata to the server,
+// surfaced through the $SRV.INFO discovery subject.
+func WithMetadata(metadata map[string]string) ServerOption {
+	return metadataOption(metadata)
+}
+
 // HandlerFunc is the function signature for handling of a single request to a stormRPC server.
 type HandlerFunc func(ctx context.Context, r Request) Response
 
@@ -74,27 +129,131 @@ type Middleware func(next HandlerFunc) HandlerFunc
 // ErrorHandler is the function signature for handling server errors.
 type ErrorHandler func(context.Context, error)
 
-// Handle registers a new HandlerFunc on the server.
-func (s *Server) Handle(subject string, fn HandlerFunc) {
-	s.handlerFuncs[subject] = fn
+// handler wraps a registered HandlerFunc together with the per-endpoint
+// monitoring counters reported via the $SRV.STATS discovery subject and
+// Server.Stats.
+type handler struct {
+	subject string
+	fn      HandlerFunc
+	stats   endpointStats
+}
+
+// Handle registers a new HandlerFunc on the server. Any mw given is applied
+// only to this handler, composed after the server's global middleware (see
+// Use). Handle may be called after Run, for example to register late-bound
+// plugins, since middleware is composed at registration time rather than
+// in a single pass over all handlers.
+func (s *Server) Handle(subject string, fn HandlerFunc, mw ...Middleware) {
+	s.register(subject, fn, composeMiddleware(s.mw, mw))
+}
+
+// register wraps fn with mw (outermost first) and stores it under subject. If
+// the server is already running, it also subscribes immediately so that
+// handlers registered after Run — for example late-bound plugins — start
+// receiving traffic right away instead of waiting for a restart.
+func (s *Server) register(subject string, fn HandlerFunc, mw []Middleware) {
+	for i := len(mw) - 1; i >= 0; i-- {
+		fn = mw[i](fn)
+	}
+
+	s.mu.Lock()
+	s.handlers[subject] = &handler{subject: subject, fn: fn}
+	running := s.running
+	s.mu.Unlock()
+
+	if running {
+		if err := s.subscribe(subject); err != nil {
+			s.errorHandler(context.Background(), err)
+		}
+	}
+}
+
+// subscribe creates the NATS queue subscription backing subject and records
+// it in s.subs so Shutdown can drain it.
+func (s *Server) subscribe(subject string) error {
+	sub, err := s.nc.QueueSubscribe(subject, s.name, s.handler)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.subs = append(s.subs, sub)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func composeMiddleware(chains ...[]Middleware) []Middleware {
+	var mw []Middleware
+	for _, c := range chains {
+		mw = append(mw, c...)
+	}
+	return mw
+}
+
+// Group returns a Group scoped to prefix, whose Handle calls are composed
+// with mw in addition to the server's global middleware. See Group.Group
+// for nesting.
+func (s *Server) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{server: s, prefix: prefix, mw: append([]Middleware{}, mw...)}
 }
 
 // Run listens on the configured subjects.
 func (s *Server) Run() error {
-	s.applyMiddlewares()
-	for k := range s.handlerFuncs {
-		_, err := s.nc.QueueSubscribe(k, s.name, s.handler)
-		if err != nil {
+	s.started = time.Now()
+
+	s.mu.Lock()
+	subjects := make([]string, 0, len(s.handlers))
+	for k := range s.handlers {
+		subjects = append(subjects, k)
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	for _, k := range subjects {
+		if err := s.subscribe(k); err != nil {
 			return err
 		}
 	}
 
+	if err := s.startDiscovery(); err != nil {
+		return err
+	}
+
 	<-s.shutdownSignal
 	return nil
 }
 
-// Shutdown stops the server.
+// Shutdown drains the server's subscriptions and waits for all in-flight
+// handler invocations to finish before closing the NATS connection, so that
+// requests already being processed complete and get a reply instead of
+// being dropped mid-deploy. If ctx expires before every handler has
+// returned, Shutdown reports the number of requests still outstanding to
+// the error handler and returns ctx.Err() without closing the connection.
 func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	subs := append(append([]*nats.Subscription{}, s.subs...), s.discoverySubs...)
+	s.mu.Unlock()
+
+	for _, sub := range subs {
+		if err := sub.Drain(); err != nil {
+			s.errorHandler(ctx, err)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.errorHandler(ctx, fmt.Errorf("stormrpc: shutdown deadline exceeded with %d request(s) still in flight", s.inFlightCount.Load()))
+		return ctx.Err()
+	}
+
 	if err := s.nc.FlushWithContext(ctx); err != nil {
 		return err
 	}
@@ -106,34 +265,37 @@ func (s *Server) Shutdown(ctx context.Context) error {
 
 // Subjects returns a list of all subjects with registered handler funcs.
 func (s *Server) Subjects() []string {
-	subs := make([]string, 0, len(s.handlerFuncs))
-	for k := range s.handlerFuncs {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs := make([]string, 0, len(s.handlers))
+	for k := range s.handlers {
 		subs = append(subs, k)
 	}
 
 	return subs
 }
 
-// Use applies all given middleware globally across all handlers.
+// Use sets the middleware applied globally across all handlers registered
+// after this call. Since middleware is composed at registration time (see
+// Handle), Use must be called before the Handle calls that should pick it
+// up.
 func (s *Server) Use(mw ...Middleware) {
 	s.mw = mw
 }
 
-func (s *Server) applyMiddlewares() {
-	for k, hf := range s.handlerFuncs {
-		for i := len(s.mw) - 1; i >= 0; i-- {
-			hf = s.mw[i](hf)
-		}
-
-		s.handlerFuncs[k] = hf
-	}
-}
-
 // handler serves the request to the specific request handler based on subject.
 // wildcard subjects are not supported as you'll need to register a handler func for each
 // rpc the server supports.
 func (s *Server) handler(msg *nats.Msg) {
-	fn := s.handlerFuncs[msg.Subject]
+	s.inFlight.Add(1)
+	s.inFlightCount.Add(1)
+	defer s.inFlight.Done()
+	defer s.inFlightCount.Add(-1)
+
+	s.mu.Lock()
+	h := s.handlers[msg.Subject]
+	s.mu.Unlock()
 
 	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
 	defer cancel()
@@ -149,21 +311,38 @@ func (s *Server) handler(msg *nats.Msg) {
 	}
 	ctx = newContextWithHeaders(ctx, req.Header)
 
-	resp := fn(ctx, req)
+	codec := codecFor(msg.Header.Get(contentTypeHeader), s.codec)
+	ctx = contextWithCodec(ctx, codec)
+	ctx = contextWithQueueGroup(ctx, s.name)
+
+	ctx, authErr := s.authenticate(ctx, req, msg.Subject)
+
+	start := time.Now()
+	var resp Response
+	if authErr != nil {
+		resp = NewErrorResponse(req, authErr)
+	} else {
+		resp = h.fn(ctx, req)
+	}
+	h.stats.record(time.Since(start), resp.Err)
+
+	if resp.Header == nil {
+		resp.Header = nats.Header{}
+	}
+	resp.Header.Set(contentTypeHeader, codec.ContentType())
 
 	if resp.Err != nil {
-		if resp.Header == nil {
-			resp.Header = nats.Header{}
-		}
 		resp.Header.Set(errorHeader, resp.Err.Error())
-		err := msg.RespondMsg(resp.Msg)
-		if err != nil {
-			s.errorHandler(ctx, err)
-		}
 	}
 
-	err := msg.RespondMsg(resp.Msg)
-	if err != nil {
+	// Requests published without a reply subject — for example a
+	// HandleStream call, whose actual replies go out over the chunk inbox
+	// carried in the Reply-To header instead — have nothing to respond to.
+	if msg.Reply == "" {
+		return
+	}
+
+	if err := msg.RespondMsg(resp.Msg); err != nil {
 		s.errorHandler(ctx, err)
 	}
 }
@@ -0,0 +1,120 @@
+package stormrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestShutdownWaitsForInFlightRequests(t *testing.T) {
+	url := startTestServer(t)
+
+	s, err := NewServer("svc", url)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s.Handle("slow", func(ctx context.Context, r Request) Response {
+		close(started)
+		<-release
+		return NewResponse(r, nil)
+	})
+
+	go func() {
+		if err := s.Run(); err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	}()
+
+	nc, err := nats.Connect(url)
+	if err != nil {
+		t.Fatalf("nats.Connect: %v", err)
+	}
+	defer nc.Close()
+
+	go func() {
+		_, _ = nc.Request("slow", nil, time.Second)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("handler never started")
+	}
+
+	shutdownDone := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownDone <- s.Shutdown(ctx)
+	}()
+
+	select {
+	case <-shutdownDone:
+		t.Fatal("Shutdown returned before the in-flight request finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case err := <-shutdownDone:
+		if err != nil {
+			t.Fatalf("Shutdown: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown never returned after the in-flight request finished")
+	}
+}
+
+func TestShutdownReportsTimeoutWithRequestsStillInFlight(t *testing.T) {
+	url := startTestServer(t)
+
+	s, err := NewServer("svc", url)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	t.Cleanup(func() { close(block) })
+
+	s.Handle("slow", func(ctx context.Context, r Request) Response {
+		close(started)
+		<-block
+		return NewResponse(r, nil)
+	})
+
+	go func() {
+		if err := s.Run(); err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	}()
+
+	nc, err := nats.Connect(url)
+	if err != nil {
+		t.Fatalf("nats.Connect: %v", err)
+	}
+	defer nc.Close()
+
+	go func() {
+		_, _ = nc.Request("slow", nil, time.Second)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("handler never started")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package stormrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Streaming chunks are carried as plain NATS messages on a per-request inbox
+// subject, tagged with these headers, so streaming stays on plain NATS core
+// with no JetStream requirement.
+const (
+	headerReplyTo     = "Reply-To"
+	headerSeq         = "Stormrpc-Seq"
+	headerEOS         = "Stormrpc-EOS"
+	headerStreamError = "Stormrpc-Error"
+)
+
+// StreamHandlerFunc is the function signature for handling a streaming RPC
+// request. It should use stream to send zero or more chunks and return once
+// the stream is complete; a non-nil error terminates the stream early and is
+// delivered to the client as a Stormrpc-Error chunk.
+type StreamHandlerFunc func(ctx context.Context, r Request, stream ServerStream) error
+
+// ServerStream lets a StreamHandlerFunc push chunks back to the client as
+// they become available, instead of buffering a single response.
+type ServerStream interface {
+	// Send publishes data as the next chunk of the stream.
+	Send(data []byte) error
+}
+
+type serverStream struct {
+	nc    *nats.Conn
+	inbox string
+	seq   uint64
+}
+
+func (s *serverStream) Send(data []byte) error {
+	seq := atomic.AddUint64(&s.seq, 1)
+
+	h := nats.Header{}
+	h.Set(headerSeq, strconv.FormatUint(seq, 10))
+
+	return s.nc.PublishMsg(&nats.Msg{Subject: s.inbox, Header: h, Data: data})
+}
+
+func (s *serverStream) close() error {
+	h := nats.Header{}
+	h.Set(headerEOS, "true")
+	return s.nc.PublishMsg(&nats.Msg{Subject: s.inbox, Header: h})
+}
+
+func (s *serverStream) closeWithError(err error) error {
+	h := nats.Header{}
+	h.Set(headerStreamError, err.Error())
+	return s.nc.PublishMsg(&nats.Msg{Subject: s.inbox, Header: h})
+}
+
+// HandleStream registers a StreamHandlerFunc on subject. The caller is
+// expected to have set the Reply-To header to a per-request inbox it is
+// already subscribed to; each chunk sent via ServerStream.Send is published
+// to that inbox tagged with a monotonically increasing Stormrpc-Seq header,
+// followed by a terminal Stormrpc-EOS message, or a Stormrpc-Error message
+// if fn returns an error.
+func (s *Server) HandleStream(subject string, fn StreamHandlerFunc) {
+	s.Handle(subject, func(ctx context.Context, r Request) Response {
+		inbox := r.Msg.Header.Get(headerReplyTo)
+		if inbox == "" {
+			return NewErrorResponse(r, fmt.Errorf("stormrpc: stream request to %q missing %s header", subject, headerReplyTo))
+		}
+
+		stream := &serverStream{nc: s.nc, inbox: inbox}
+
+		if err := fn(ctx, r, stream); err != nil {
+			if sendErr := stream.closeWithError(err); sendErr != nil {
+				s.errorHandler(ctx, sendErr)
+			}
+			return NewErrorResponse(r, err)
+		}
+
+		if err := stream.close(); err != nil {
+			s.errorHandler(ctx, err)
+		}
+
+		return NewResponse(r, nil)
+	})
+}
+
+// ClientStream yields the chunks of a streaming RPC response in order.
+type ClientStream interface {
+	// Recv blocks for the next chunk, returning io.EOF once the stream has
+	// ended, the error reported by the server if it failed mid-stream, or
+	// ctx.Err() if the stream's context is canceled first.
+	Recv() ([]byte, error)
+}
+
+type clientStream struct {
+	ctx context.Context
+	sub *nats.Subscription
+}
+
+func (c *clientStream) Recv() ([]byte, error) {
+	msg, err := c.sub.NextMsgWithContext(c.ctx)
+	if err != nil {
+		_ = c.sub.Unsubscribe()
+		return nil, err
+	}
+
+	if msg.Header != nil {
+		if errMsg := msg.Header.Get(headerStreamError); errMsg != "" {
+			_ = c.sub.Unsubscribe()
+			return nil, errors.New(errMsg)
+		}
+
+		if msg.Header.Get(headerEOS) == "true" {
+			_ = c.sub.Unsubscribe()
+			return nil, io.EOF
+		}
+	}
+
+	return msg.Data, nil
+}
+
+// Stream issues req as a streaming RPC call: it subscribes to a fresh inbox
+// before publishing the request, so the server can begin sending chunks as
+// soon as it starts processing, then returns a ClientStream that yields
+// those chunks in order until the server signals end-of-stream.
+func (c *Client) Stream(ctx context.Context, req Request) (ClientStream, error) {
+	inbox := nats.NewInbox()
+
+	sub, err := c.nc.SubscribeSync(inbox)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Msg.Header == nil {
+		req.Msg.Header = nats.Header{}
+	}
+	req.Msg.Header.Set(headerReplyTo, inbox)
+
+	if err := c.nc.PublishMsg(req.Msg); err != nil {
+		_ = sub.Unsubscribe()
+		return nil, err
+	}
+
+	return &clientStream{ctx: ctx, sub: sub}, nil
+}
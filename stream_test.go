@@ -0,0 +1,184 @@
+package stormrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestStreamRoundTrip(t *testing.T) {
+	url := startTestServer(t)
+
+	s, err := NewServer("svc", url)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	chunks := [][]byte{[]byte("one"), []byte("two"), []byte("three")}
+	s.HandleStream("count", func(ctx context.Context, r Request, stream ServerStream) error {
+		for _, c := range chunks {
+			if err := stream.Send(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	go func() {
+		if err := s.Run(); err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+	})
+
+	c, err := NewClient(url)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := c.Stream(ctx, NewRequest("count", nil))
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+
+	var got [][]byte
+	for {
+		data, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		got = append(got, data)
+	}
+
+	if len(got) != len(chunks) {
+		t.Fatalf("expected %d chunks, got %d", len(chunks), len(got))
+	}
+	for i, c := range chunks {
+		if string(got[i]) != string(c) {
+			t.Fatalf("chunk %d: expected %q, got %q", i, c, got[i])
+		}
+	}
+}
+
+func TestStreamRoundTripReportsNoSpuriousError(t *testing.T) {
+	url := startTestServer(t)
+
+	var handlerErrs []error
+	s, err := NewServer("svc", url, WithErrorHandler(func(ctx context.Context, err error) {
+		handlerErrs = append(handlerErrs, err)
+	}))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	s.HandleStream("count", func(ctx context.Context, r Request, stream ServerStream) error {
+		return stream.Send([]byte("one"))
+	})
+
+	go func() {
+		if err := s.Run(); err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+	})
+
+	c, err := NewClient(url)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := c.Stream(ctx, NewRequest("count", nil))
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if err != io.EOF {
+				t.Fatalf("Recv: %v", err)
+			}
+			break
+		}
+	}
+
+	if len(handlerErrs) != 0 {
+		t.Fatalf("expected no errors reported to the error handler, got %v", handlerErrs)
+	}
+}
+
+func TestStreamRoundTripError(t *testing.T) {
+	url := startTestServer(t)
+
+	s, err := NewServer("svc", url)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	s.HandleStream("fail", func(ctx context.Context, r Request, stream ServerStream) error {
+		if err := stream.Send([]byte("partial")); err != nil {
+			return err
+		}
+		return errors.New("boom")
+	})
+
+	go func() {
+		if err := s.Run(); err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+	})
+
+	c, err := NewClient(url)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := c.Stream(ctx, NewRequest("fail", nil))
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv first chunk: %v", err)
+	}
+
+	_, err = stream.Recv()
+	if err == nil || err == io.EOF {
+		t.Fatalf("expected a stream error, got %v", err)
+	}
+	if err.Error() != "boom" {
+		t.Fatalf("expected error %q, got %q", "boom", err.Error())
+	}
+}
@@ -0,0 +1,21 @@
+package stormrpc
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats-server/v2/test"
+)
+
+// startTestServer starts an in-process NATS server on a random port for the
+// duration of the test and returns its client URL.
+func startTestServer(t *testing.T) string {
+	t.Helper()
+
+	opts := test.DefaultTestOptions
+	opts.Port = -1
+
+	s := test.RunServer(&opts)
+	t.Cleanup(s.Shutdown)
+
+	return s.ClientURL()
+}
@@ -0,0 +1,49 @@
+package stormrpc
+
+import "context"
+
+type codecCtxKey struct{}
+
+// contextWithCodec attaches the Codec negotiated for the current request so
+// HandleTyped handlers can encode/decode without re-deriving it.
+func contextWithCodec(ctx context.Context, c Codec) context.Context {
+	return context.WithValue(ctx, codecCtxKey{}, c)
+}
+
+// codecFromContext returns the Codec negotiated for the current request, or
+// def if none was attached (e.g. when called outside of a stormRPC handler).
+func codecFromContext(ctx context.Context, def Codec) Codec {
+	if c, ok := ctx.Value(codecCtxKey{}).(Codec); ok {
+		return c
+	}
+	return def
+}
+
+// HandleTyped registers a generic, codec-aware handler for subject on s.
+// Go methods cannot carry their own type parameters, so unlike Handle this
+// is a package-level function taking the Server explicitly. The request
+// body is decoded into a *Req using the Codec negotiated for the request,
+// fn is invoked, and the returned *Resp is encoded back with the same
+// Codec, so callers no longer hand-roll marshaling in every handler.
+func HandleTyped[Req, Resp any](s *Server, subject string, fn func(ctx context.Context, req *Req) (*Resp, error)) {
+	s.Handle(subject, func(ctx context.Context, r Request) Response {
+		codec := codecFromContext(ctx, s.codec)
+
+		req := new(Req)
+		if err := codec.Unmarshal(r.Msg.Data, req); err != nil {
+			return NewErrorResponse(r, err)
+		}
+
+		resp, err := fn(ctx, req)
+		if err != nil {
+			return NewErrorResponse(r, err)
+		}
+
+		data, err := codec.Marshal(resp)
+		if err != nil {
+			return NewErrorResponse(r, err)
+		}
+
+		return NewResponse(r, data)
+	})
+}